@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// BasicAuthConfig holds static basic auth credentials.
+type BasicAuthConfig struct {
+	Username string
+	Password string
+}
+
+// BearerTokenFileConfig re-reads a bearer token from disk on every request
+// whose mtime has changed, so a long-running migration keeps working
+// against sources that rotate short-lived tokens (e.g. vmauth or VM Cloud
+// fronted by an IAM sidecar) without a vmctl restart.
+type BearerTokenFileConfig struct {
+	// Path is the file holding the raw bearer token.
+	Path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+}
+
+func (b *BearerTokenFileConfig) get() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	fi, err := os.Stat(b.Path)
+	if err != nil {
+		return "", fmt.Errorf("cannot stat bearer token file %q: %w", b.Path, err)
+	}
+	if b.token != "" && !fi.ModTime().After(b.modTime) {
+		return b.token, nil
+	}
+
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read bearer token file %q: %w", b.Path, err)
+	}
+	b.token = strings.TrimSpace(string(data))
+	b.modTime = fi.ModTime()
+	return b.token, nil
+}
+
+// OAuth2Config configures an OAuth2 client-credentials flow used to obtain
+// and transparently refresh short-lived access tokens ahead of expiry.
+type OAuth2Config struct {
+	ClientID       string
+	ClientSecret   string
+	TokenURL       string
+	Scopes         []string
+	EndpointParams map[string][]string
+
+	once   sync.Once
+	client *http.Client
+}
+
+// httpClient returns an *http.Client whose Transport attaches and refreshes
+// the OAuth2 access token automatically. base, when set, is threaded through
+// via the oauth2.HTTPClient context key so its Transport (custom TLS config,
+// proxy, CA pool, e.g. for vmauth or VM Cloud endpoints) backs both the token
+// requests and the resulting client's Transport, instead of silently falling
+// back to http.DefaultTransport.
+func (o *OAuth2Config) httpClient(base *http.Client) *http.Client {
+	o.once.Do(func() {
+		cc := clientcredentials.Config{
+			ClientID:       o.ClientID,
+			ClientSecret:   o.ClientSecret,
+			TokenURL:       o.TokenURL,
+			Scopes:         o.Scopes,
+			EndpointParams: o.EndpointParams,
+		}
+		ctx := context.Background()
+		if base != nil {
+			ctx = context.WithValue(ctx, oauth2.HTTPClient, base)
+		}
+		o.client = cc.Client(ctx)
+	})
+	return o.client
+}
+
+// Config holds auth parameters used for vmctl requests against the source
+// and destination clusters. At most one of BasicAuth, Bearer,
+// BearerTokenFile or OAuth2 is expected to be set.
+type Config struct {
+	// BasicAuth holds static basic auth credentials, if set.
+	BasicAuth *BasicAuthConfig
+	// Bearer is a static bearer token, set as the Authorization header.
+	Bearer string
+	// BearerTokenFile re-reads the bearer token from disk, picking up
+	// rotations without a vmctl restart.
+	BearerTokenFile *BearerTokenFileConfig
+	// OAuth2 configures a client-credentials flow that transparently
+	// refreshes the access token before it expires. When set, HTTPClient
+	// must be used to obtain the *http.Client that carries the token --
+	// SetHeaders is a no-op for this mode.
+	OAuth2 *OAuth2Config
+}
+
+// SetHeaders sets the configured auth headers on req. isSource distinguishes
+// the source from the destination cluster, since the two can carry
+// independent credentials.
+func (ac *Config) SetHeaders(req *http.Request, isSource bool) {
+	switch {
+	case ac.OAuth2 != nil:
+		// the oauth2 transport returned by HTTPClient attaches the
+		// Authorization header itself.
+	case ac.BearerTokenFile != nil:
+		token, err := ac.BearerTokenFile.get()
+		if err != nil {
+			log.Printf("cannot refresh bearer token for %s request: %s", sourceLabel(isSource), err)
+			return
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case ac.Bearer != "":
+		req.Header.Set("Authorization", "Bearer "+ac.Bearer)
+	case ac.BasicAuth != nil:
+		req.SetBasicAuth(ac.BasicAuth.Username, ac.BasicAuth.Password)
+	}
+}
+
+// HTTPClient returns base wrapped with the OAuth2 client-credentials
+// transport when OAuth2 is configured, or base unchanged otherwise.
+func (ac *Config) HTTPClient(base *http.Client) *http.Client {
+	if ac.OAuth2 == nil {
+		return base
+	}
+	oc := ac.OAuth2.httpClient(base)
+	if base != nil {
+		oc.Timeout = base.Timeout
+	}
+	return oc
+}
+
+func sourceLabel(isSource bool) string {
+	if isSource {
+		return "source"
+	}
+	return "destination"
+}
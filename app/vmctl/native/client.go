@@ -3,13 +3,19 @@ package native
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/stepper"
@@ -19,6 +25,10 @@ import (
 const (
 	nativeTenantsAddr     = "admin/tenants"
 	nativeMetricNamesAddr = "api/v1/label/__name__/values"
+
+	// defaultExploreConcurrency bounds the number of in-flight Explore
+	// requests when Client.ExploreConcurrency is unset.
+	defaultExploreConcurrency = 8
 )
 
 // Client is an HTTP client for exporting and importing
@@ -28,6 +38,98 @@ type Client struct {
 	Addr        string
 	ExtraLabels []string
 	HTTPClient  *http.Client
+
+	// ExploreConcurrency bounds the number of in-flight requests issued by
+	// Explore. Defaults to defaultExploreConcurrency when <= 0.
+	ExploreConcurrency int
+	// RequestsPerSecond and Burst configure an optional rate limiter shared
+	// across Explore's in-flight requests. RequestsPerSecond <= 0 (the
+	// default) disables rate limiting.
+	RequestsPerSecond float64
+	Burst             int
+
+	// Progress, if set, collects Prometheus-format progress metrics for
+	// the requests this Client makes. See NewProgress.
+	Progress *Progress
+
+	limiterOnce sync.Once
+	limiterMu   sync.Mutex
+	limiter     *rate.Limiter
+
+	transportOnce sync.Once
+	transport     *http.Client
+}
+
+// transportClient returns the *http.Client requests are actually issued on:
+// HTTPClient wrapped with AuthCfg's OAuth2 transport when OAuth2 is
+// configured, or HTTPClient unchanged otherwise.
+func (c *Client) transportClient() *http.Client {
+	c.transportOnce.Do(func() {
+		c.transport = c.HTTPClient
+		if c.AuthCfg != nil {
+			c.transport = c.AuthCfg.HTTPClient(c.HTTPClient)
+		}
+	})
+	return c.transport
+}
+
+// rateLimiter lazily creates the shared limiter from RequestsPerSecond and
+// Burst, or returns nil when rate limiting is disabled.
+func (c *Client) rateLimiter() *rate.Limiter {
+	if c.RequestsPerSecond <= 0 {
+		return nil
+	}
+	c.limiterOnce.Do(func() {
+		burst := c.Burst
+		if burst <= 0 {
+			burst = int(c.RequestsPerSecond)
+			if burst < 1 {
+				burst = 1
+			}
+		}
+		c.limiter = rate.NewLimiter(rate.Limit(c.RequestsPerSecond), burst)
+	})
+	return c.limiter
+}
+
+// throttle halves the limiter's rate, to no less than 1 request per second
+// (AIMD-style backoff), in response to a
+// 429 from the source, honoring Retry-After when the source sent one.
+func (c *Client) throttle(retryAfter time.Duration) {
+	c.limiterMu.Lock()
+	defer c.limiterMu.Unlock()
+	if c.limiter == nil {
+		return
+	}
+	newLimit := c.limiter.Limit() / 2
+	if newLimit < 1 {
+		newLimit = 1
+	}
+	if retryAfter > 0 {
+		c.limiter.SetLimitAt(time.Now().Add(retryAfter), newLimit)
+		return
+	}
+	c.limiter.SetLimit(newLimit)
+}
+
+// recoverRate additively increases the limiter's rate after a successful
+// request, up to the originally configured RequestsPerSecond.
+func (c *Client) recoverRate() {
+	c.limiterMu.Lock()
+	defer c.limiterMu.Unlock()
+	if c.limiter == nil {
+		return
+	}
+	cur := float64(c.limiter.Limit())
+	max := c.RequestsPerSecond
+	if cur >= max {
+		return
+	}
+	next := cur + 1
+	if next > max {
+		next = max
+	}
+	c.limiter.SetLimit(rate.Limit(next))
 }
 
 // LabelValues represents series from api/v1/series response
@@ -65,13 +167,41 @@ func (c *Client) Explore(ctx context.Context, f Filter, tenantID string) ([]stri
 		return nil, fmt.Errorf("failed to create date ranges for explore metrics: %w", err)
 	}
 
+	ctx, exploreSpan := tracer.Start(ctx, "vmctl.native.Explore", trace.WithAttributes(filterAttributes(tenantID, f)...))
+	defer exploreSpan.End()
+
+	concurrency := c.ExploreConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultExploreConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	limiter := c.rateLimiter()
+
 	var metricNames []string
 	errs, ctx := errgroup.WithContext(ctx)
 	metricNamesC := make(chan []string)
 	for _, times := range ranges {
 		start := times[0].Format(time.RFC3339)
 		end := times[1].Format(time.RFC3339)
-		errs.Go(func() error {
+		errs.Go(func() (rangeErr error) {
+			rangeCtx, rangeSpan := tracer.Start(ctx, "vmctl.native.Explore.chunk",
+				trace.WithAttributes(attribute.String("vmctl.time_start", start), attribute.String("vmctl.time_end", end)))
+			defer func() { endSpan(rangeSpan, rangeErr) }()
+			ctx := rangeCtx
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			defer func() { <-sem }()
+
+			if limiter != nil {
+				if err := limiter.Wait(ctx); err != nil {
+					return err
+				}
+			}
+
 			url := fmt.Sprintf("%s/%s", c.Addr, nativeMetricNamesAddr)
 			if tenantID != "" {
 				url = fmt.Sprintf("%s/select/%s/prometheus/%s", c.Addr, tenantID, nativeMetricNamesAddr)
@@ -93,8 +223,13 @@ func (c *Client) Explore(ctx context.Context, f Filter, tenantID string) ([]stri
 
 			resp, err := c.do(req, http.StatusOK)
 			if err != nil {
+				var se *StatusError
+				if errors.As(err, &se) && se.StatusCode == http.StatusTooManyRequests {
+					c.throttle(se.RetryAfter)
+				}
 				return fmt.Errorf("series request failed: %s", err)
 			}
+			c.recoverRate()
 
 			var response Response
 			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
@@ -128,8 +263,21 @@ func (c *Client) Explore(ctx context.Context, f Filter, tenantID string) ([]stri
 }
 
 // ImportPipe uses pipe reader in request to process data
-func (c *Client) ImportPipe(ctx context.Context, dstURL string, pr *io.PipeReader) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dstURL, pr)
+func (c *Client) ImportPipe(ctx context.Context, dstURL string, pr *io.PipeReader) (err error) {
+	ctx, span := tracer.Start(ctx, "vmctl.native.ImportPipe")
+	defer func() { endSpan(span, err) }()
+
+	// counted stays an io.ReadCloser throughout so http.NewRequest never
+	// falls back to io.NopCloser for the request body -- otherwise pr is
+	// never closed and a failed import leaves migrateTenant's writer
+	// goroutine blocked on pw.Write forever.
+	counted := &byteCountingReader{rc: pr}
+	var body io.ReadCloser = counted
+	if c.Progress != nil {
+		body = newCountingReadCloser(counted, c.Progress.bytesWritten)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dstURL, body)
 	if err != nil {
 		return fmt.Errorf("cannot create import request to %q: %s", c.Addr, err)
 	}
@@ -138,6 +286,7 @@ func (c *Client) ImportPipe(ctx context.Context, dstURL string, pr *io.PipeReade
 	if err != nil {
 		return fmt.Errorf("import request failed: %s", err)
 	}
+	span.SetAttributes(attribute.Int64("vmctl.bytes", counted.n))
 	if err := importResp.Body.Close(); err != nil {
 		return fmt.Errorf("cannot close import response body: %s", err)
 	}
@@ -145,10 +294,14 @@ func (c *Client) ImportPipe(ctx context.Context, dstURL string, pr *io.PipeReade
 }
 
 // ExportPipe makes request by provided filter and return io.ReadCloser which can be used to get data
-func (c *Client) ExportPipe(ctx context.Context, url string, f Filter) (io.ReadCloser, error) {
+func (c *Client) ExportPipe(ctx context.Context, url string, f Filter) (_ io.ReadCloser, err error) {
+	ctx, span := tracer.Start(ctx, "vmctl.native.ExportPipe", trace.WithAttributes(filterAttributes("", f)...))
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create request to %q: %s", c.Addr, err)
+		err = fmt.Errorf("cannot create request to %q: %s", c.Addr, err)
+		endSpan(span, err)
+		return nil, err
 	}
 
 	params := req.URL.Query()
@@ -166,13 +319,25 @@ func (c *Client) ExportPipe(ctx context.Context, url string, f Filter) (io.ReadC
 
 	resp, err := c.do(req, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("export request failed: %w", err)
+		err = fmt.Errorf("export request failed: %w", err)
+		endSpan(span, err)
+		return nil, err
+	}
+
+	rc := resp.Body
+	if c.Progress != nil {
+		rc = newCountingReadCloser(rc, c.Progress.bytesRead)
 	}
-	return resp.Body, nil
+	// the span ends when the returned reader is closed, so it covers the
+	// whole transfer rather than just the initial request.
+	return &tracedReadCloser{ReadCloser: rc, span: span}, nil
 }
 
 // GetSourceTenants discovers tenants by provided filter
-func (c *Client) GetSourceTenants(ctx context.Context, f Filter) ([]string, error) {
+func (c *Client) GetSourceTenants(ctx context.Context, f Filter) (_ []string, err error) {
+	ctx, span := tracer.Start(ctx, "vmctl.native.GetSourceTenants")
+	defer func() { endSpan(span, err) }()
+
 	u := fmt.Sprintf("%s/%s", c.Addr, nativeTenantsAddr)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
@@ -207,22 +372,72 @@ func (c *Client) GetSourceTenants(ctx context.Context, f Filter) ([]string, erro
 	return r.Tenants, nil
 }
 
-func (c *Client) do(req *http.Request, expSC int) (*http.Response, error) {
+func (c *Client) do(req *http.Request, expSC int) (resp *http.Response, err error) {
+	ctx, span := tracer.Start(req.Context(), "vmctl.native.do")
+	defer func() { endSpan(span, err) }()
+	req = req.WithContext(ctx)
+
 	if c.AuthCfg != nil {
 		c.AuthCfg.SetHeaders(req, true)
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	if c.Progress != nil {
+		c.Progress.inflightRequests.Inc()
+		defer c.Progress.inflightRequests.Dec()
+		start := time.Now()
+		defer func() { c.Progress.requestDuration.Update(time.Since(start).Seconds()) }()
+	}
+
+	resp, err = c.transportClient().Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("unexpected error when performing request: %w", err)
+		err = fmt.Errorf("unexpected error when performing request: %w", err)
+		return nil, err
 	}
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
 	if resp.StatusCode != expSC {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response body for status code %d: %s", resp.StatusCode, err)
+		body, berr := io.ReadAll(resp.Body)
+		if berr != nil {
+			err = fmt.Errorf("failed to read response body for status code %d: %s", resp.StatusCode, berr)
+			return nil, err
+		}
+		se := &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			se.RetryAfter = parseRetryAfter(ra)
+		}
+		err = se
+		return nil, err
+	}
+	return resp, nil
+}
+
+// StatusError is returned by Client.do when the server responds with a
+// status code other than the one the caller expected. It carries enough
+// detail for callers to decide whether the request is worth retrying.
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the parsed Retry-After header, if the server sent one.
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response code %d: %s", e.StatusCode, e.Body)
+}
+
+// parseRetryAfter parses a Retry-After header value, which per RFC 7231 is
+// either a number of seconds or an HTTP-date. Unparsable values are ignored.
+func parseRetryAfter(v string) time.Duration {
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
 		}
-		return nil, fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, string(body))
 	}
-	return resp, err
+	return 0
 }
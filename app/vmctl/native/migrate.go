@@ -0,0 +1,189 @@
+package native
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TenantProgress reports one tenant's migration outcome, sent on the
+// channel passed as MigrateAllTenantsOptions.Progress.
+type TenantProgress struct {
+	Tenant string
+	// Bytes is the number of bytes read from the source for this tenant.
+	Bytes    int64
+	Duration time.Duration
+	Err      error
+}
+
+// MigrateAllTenantsOptions configures MigrateAllTenants.
+type MigrateAllTenantsOptions struct {
+	Filter Filter
+	// Concurrency bounds the number of tenants migrated in parallel.
+	// Defaults to 1 when <= 0.
+	Concurrency int
+	// TenantMap optionally remaps a source tenant ID to a destination
+	// tenant ID. Tenants absent from the map keep their source ID.
+	TenantMap map[string]string
+	// FailFast cancels the remaining tenant migrations as soon as one
+	// tenant fails. When false (the default), every tenant is attempted
+	// and the failures are returned together as a *MigrationError.
+	FailFast bool
+	// Progress, if non-nil, receives a TenantProgress update as each
+	// started tenant migration completes (or fails), and is closed once
+	// every started migration has returned. Once the run is cancelled (via
+	// ctx or FailFast), tenants not yet started are skipped and updates may
+	// be dropped.
+	Progress chan<- TenantProgress
+}
+
+// MigrationError collects the per-tenant errors of a MigrateAllTenants run
+// that did not use FailFast.
+type MigrationError struct {
+	Errs map[string]error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("%d of the migrated tenants failed", len(e.Errs))
+}
+
+// MigrateAllTenants discovers tenants on src via GetSourceTenants and
+// migrates each of them to dst concurrently, exporting from src's
+// /select/<tenant>/... endpoint and importing into dst's
+// /insert/<tenant>/... endpoint (remapped via opts.TenantMap when set). A
+// single tenant's failure does not abort the others unless opts.FailFast
+// is set.
+func MigrateAllTenants(ctx context.Context, src, dst *Client, opts MigrateAllTenantsOptions) error {
+	ctx, span := tracer.Start(ctx, "vmctl.migrate")
+	defer span.End()
+
+	tenants, err := src.GetSourceTenants(ctx, opts.Filter)
+	if err != nil {
+		return fmt.Errorf("cannot discover source tenants: %w", err)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := make(map[string]error)
+
+	for _, tenant := range tenants {
+		tenant := tenant
+
+		select {
+		case sem <- struct{}{}:
+		case <-runCtx.Done():
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			dstTenant := tenant
+			if mapped, ok := opts.TenantMap[tenant]; ok {
+				dstTenant = mapped
+			}
+
+			started := time.Now()
+			bytesN, mErr := migrateTenant(runCtx, src, dst, tenant, dstTenant, opts.Filter)
+
+			if opts.Progress != nil {
+				select {
+				case opts.Progress <- TenantProgress{
+					Tenant:   tenant,
+					Bytes:    bytesN,
+					Duration: time.Since(started),
+					Err:      mErr,
+				}:
+				case <-runCtx.Done():
+				}
+			}
+
+			if mErr != nil {
+				mu.Lock()
+				errs[tenant] = mErr
+				mu.Unlock()
+				if opts.FailFast {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	if opts.Progress != nil {
+		close(opts.Progress)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	if opts.FailFast {
+		for tenant, err := range errs {
+			return fmt.Errorf("tenant %q migration failed: %w", tenant, err)
+		}
+	}
+	return &MigrationError{Errs: errs}
+}
+
+// migrateTenant runs a single export/import pipeline for tenant, writing to
+// dst's tenant dstTenant, and returns the number of bytes transferred.
+func migrateTenant(ctx context.Context, src, dst *Client, tenant, dstTenant string, f Filter) (bytesN int64, err error) {
+	ctx, span := tracer.Start(ctx, "vmctl.native.migrateTenant", trace.WithAttributes(filterAttributes(tenant, f)...))
+	defer func() {
+		span.SetAttributes(attribute.Int64("vmctl.bytes", bytesN))
+		endSpan(span, err)
+	}()
+
+	exportURL := fmt.Sprintf("%s/select/%s/prometheus/api/v1/export/native", src.Addr, tenant)
+	importURL := fmt.Sprintf("%s/insert/%s/prometheus/api/v1/import/native", dst.Addr, dstTenant)
+
+	rc, err := src.ExportPipe(ctx, exportURL, f)
+	if err != nil {
+		return 0, fmt.Errorf("cannot export tenant %q: %w", tenant, err)
+	}
+	defer rc.Close()
+
+	cr := &countingReader{r: rc}
+	pr, pw := io.Pipe()
+	go func() {
+		_, copyErr := io.Copy(pw, cr)
+		pw.CloseWithError(copyErr)
+	}()
+
+	if err := dst.ImportPipe(ctx, importURL, pr); err != nil {
+		return cr.n, fmt.Errorf("cannot import tenant %q into %q: %w", tenant, dstTenant, err)
+	}
+	if src.Progress != nil {
+		src.Progress.chunksCompleted(tenant).Inc()
+	}
+	return cr.n, nil
+}
+
+// countingReader wraps an io.Reader, tallying the number of bytes read
+// through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,349 @@
+package native
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	nativeQueryAddr      = "api/v1/query"
+	nativeQueryRangeAddr = "api/v1/query_range"
+)
+
+// Series is a single time series decoded from a query/query_range response.
+type Series struct {
+	Metric map[string]string `json:"metric"`
+	// Values holds the `[timestamp, value]` pairs of a query_range (matrix) result.
+	Values [][2]interface{} `json:"values,omitempty"`
+	// Value holds the single `[timestamp, value]` pair of a query (vector) result.
+	Value [2]interface{} `json:"value,omitempty"`
+}
+
+// StepSamples is the number of samples scanned for a single evaluation step.
+type StepSamples struct {
+	Timestamp int64
+	Samples   int64
+}
+
+// QueryStats holds the `stats.samples` block returned by the source when
+// `stats=all` is requested, as exposed via Prometheus' query stats feature flag.
+type QueryStats struct {
+	// TotalQueryableSamples is the total number of samples scanned across all steps.
+	TotalQueryableSamples int64
+	// PerStep is the number of samples scanned per evaluation step, in chronological order.
+	PerStep []StepSamples
+}
+
+// QueryResult holds the metadata of a query/query_range response: its result
+// type and, if `stats=all` was requested, the query's samples stats.
+type QueryResult struct {
+	ResultType string
+	Stats      *QueryStats
+}
+
+// SeriesIterator streams the series of a query/query_range response one at a
+// time instead of buffering the whole decoded result in memory. Callers must
+// call Close once done, whether or not Next has been drained to io.EOF.
+type SeriesIterator struct {
+	rc  io.ReadCloser
+	dec *json.Decoder
+
+	inResult bool
+	done     bool
+	result   QueryResult
+}
+
+// query issues a GET request against addr with the given query params,
+// optionally requesting `stats=all`, and returns an iterator over the
+// resulting series.
+func (c *Client) query(ctx context.Context, addr string, params map[string]string, withStats bool) (*SeriesIterator, error) {
+	u := fmt.Sprintf("%s/%s", c.Addr, addr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request to %q: %s", u, err)
+	}
+
+	q := req.URL.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	if withStats {
+		q.Set("stats", "all")
+	}
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("query request failed: %w", err)
+	}
+
+	it, err := newSeriesIterator(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read query response from %q: %w", u, err)
+	}
+	return it, nil
+}
+
+// Query issues an instant PromQL query against api/v1/query. t is the
+// evaluation timestamp, in the same format accepted by Prometheus (e.g. an
+// RFC3339 string or unix timestamp); pass "" to use the server's current time.
+// When withStats is true, the source is asked for `stats=all` and the
+// per-step samples scanned are available via the returned iterator's Result
+// once Next has been drained to io.EOF.
+func (c *Client) Query(ctx context.Context, query, t string, withStats bool) (*SeriesIterator, error) {
+	params := map[string]string{"query": query}
+	if t != "" {
+		params["time"] = t
+	}
+	return c.query(ctx, nativeQueryAddr, params, withStats)
+}
+
+// QueryRange issues a ranged PromQL query against api/v1/query_range.
+// When withStats is true, the source is asked for `stats=all` and the
+// per-step samples scanned are available via the returned iterator's Result
+// once Next has been drained to io.EOF.
+func (c *Client) QueryRange(ctx context.Context, query, start, end, step string, withStats bool) (*SeriesIterator, error) {
+	params := map[string]string{"query": query}
+	if start != "" {
+		params["start"] = start
+	}
+	if end != "" {
+		params["end"] = end
+	}
+	if step != "" {
+		params["step"] = step
+	}
+	return c.query(ctx, nativeQueryRangeAddr, params, withStats)
+}
+
+func newSeriesIterator(rc io.ReadCloser) (*SeriesIterator, error) {
+	it := &SeriesIterator{rc: rc, dec: json.NewDecoder(rc)}
+	if err := it.enterResult(); err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return it, nil
+}
+
+// enterResult walks the response object up to the `data.result` array,
+// leaving the decoder positioned to read its elements one by one via Next.
+func (it *SeriesIterator) enterResult() error {
+	if err := expectDelim(it.dec, '{'); err != nil {
+		return fmt.Errorf("unexpected response format: %w", err)
+	}
+	for it.dec.More() {
+		key, err := it.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "status":
+			var status string
+			if err := it.dec.Decode(&status); err != nil {
+				return fmt.Errorf("cannot decode status: %w", err)
+			}
+			if status != "success" {
+				return fmt.Errorf("query returned non-success status %q", status)
+			}
+		case "error", "errorType":
+			var s string
+			if err := it.dec.Decode(&s); err != nil {
+				return err
+			}
+			return fmt.Errorf("query failed: %s", s)
+		case "data":
+			if err := expectDelim(it.dec, '{'); err != nil {
+				return err
+			}
+			for it.dec.More() {
+				dataKey, err := it.dec.Token()
+				if err != nil {
+					return err
+				}
+				if dataKey == "resultType" {
+					if err := it.dec.Decode(&it.result.ResultType); err != nil {
+						return fmt.Errorf("cannot decode resultType: %w", err)
+					}
+					continue
+				}
+				if dataKey == "result" {
+					if err := expectDelim(it.dec, '['); err != nil {
+						return err
+					}
+					it.inResult = true
+					return nil
+				}
+				if err := discardValue(it.dec); err != nil {
+					return err
+				}
+			}
+			// empty data object: consume its closing '}'
+			if _, err := it.dec.Token(); err != nil {
+				return err
+			}
+		case "stats":
+			stats, err := decodeQueryStats(it.dec)
+			if err != nil {
+				return err
+			}
+			it.result.Stats = stats
+		default:
+			if err := discardValue(it.dec); err != nil {
+				return err
+			}
+		}
+	}
+	it.done = true
+	return nil
+}
+
+// Next decodes the next series in the result array. It returns io.EOF once
+// all series have been consumed, at which point Result becomes valid.
+func (it *SeriesIterator) Next() (*Series, error) {
+	if !it.inResult {
+		return nil, io.EOF
+	}
+	if !it.dec.More() {
+		if _, err := it.dec.Token(); err != nil { // closing ']' of result
+			return nil, err
+		}
+		it.inResult = false
+		if err := it.finish(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	var s Series
+	if err := it.dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("cannot decode series: %w", err)
+	}
+	return &s, nil
+}
+
+// finish walks the remainder of the response after the result array has
+// been drained: the remaining `data` fields (Prometheus places `stats`
+// there, after `result`), the closing `data` object and any trailing
+// top-level fields such as `stats`.
+func (it *SeriesIterator) finish() error {
+	readFields := func() error {
+		for it.dec.More() {
+			key, err := it.dec.Token()
+			if err != nil {
+				return err
+			}
+			if key == "stats" {
+				stats, err := decodeQueryStats(it.dec)
+				if err != nil {
+					return err
+				}
+				it.result.Stats = stats
+				continue
+			}
+			if err := discardValue(it.dec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := readFields(); err != nil { // remaining fields of data
+		return err
+	}
+	if _, err := it.dec.Token(); err != nil { // closing '}' of data
+		return err
+	}
+	if err := readFields(); err != nil { // trailing top-level fields
+		return err
+	}
+	it.done = true
+	return nil
+}
+
+// Result returns the response's metadata. It is only populated once Next
+// has returned io.EOF.
+func (it *SeriesIterator) Result() *QueryResult {
+	if !it.done {
+		return nil
+	}
+	return &it.result
+}
+
+// Close closes the underlying response body.
+func (it *SeriesIterator) Close() error {
+	return it.rc.Close()
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	d, ok := tok.(json.Delim)
+	if !ok || d != want {
+		return fmt.Errorf("unexpected token %v, want %q", tok, want)
+	}
+	return nil
+}
+
+func discardValue(dec *json.Decoder) error {
+	var v interface{}
+	return dec.Decode(&v)
+}
+
+type rawQueryStats struct {
+	Samples struct {
+		TotalQueryableSamples        int64            `json:"totalQueryableSamples"`
+		TotalQueryableSamplesPerStep [][2]interface{} `json:"totalQueryableSamplesPerStep"`
+	} `json:"samples"`
+}
+
+func decodeQueryStats(dec *json.Decoder) (*QueryStats, error) {
+	var raw rawQueryStats
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("cannot decode stats: %w", err)
+	}
+	qs := &QueryStats{TotalQueryableSamples: raw.Samples.TotalQueryableSamples}
+	for _, pair := range raw.Samples.TotalQueryableSamplesPerStep {
+		ts, samples, err := parseStepPair(pair)
+		if err != nil {
+			return nil, err
+		}
+		qs.PerStep = append(qs.PerStep, StepSamples{Timestamp: ts, Samples: samples})
+	}
+	return qs, nil
+}
+
+// parseStepPair decodes a `[timestamp, samples]` pair as emitted by
+// Prometheus' per-step query stats. Unlike result *values*, the sample
+// count is a plain JSON number (decoded by encoding/json as float64), not a
+// string; a string is tolerated too in case a future source encodes it
+// that way.
+func parseStepPair(pair [2]interface{}) (int64, int64, error) {
+	ts, ok := pair[0].(float64)
+	if !ok {
+		return 0, 0, fmt.Errorf("unexpected timestamp type %T in stats", pair[0])
+	}
+	samples, err := parseStatSamples(pair[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(ts), samples, nil
+}
+
+func parseStatSamples(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case float64:
+		return int64(val), nil
+	case string:
+		samples, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse samples count %q: %w", val, err)
+		}
+		return samples, nil
+	default:
+		return 0, fmt.Errorf("unexpected samples value type %T in stats", v)
+	}
+}
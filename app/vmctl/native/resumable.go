@@ -0,0 +1,264 @@
+package native
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/stepper"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/utils"
+)
+
+// checkpointVersion is bumped whenever the checkpoint file format changes in
+// a way older vmctl releases can't read.
+const checkpointVersion = 1
+
+// Checkpoint tracks the progress of a ResumableExport so it can resume from
+// the last durably processed chunk after a restart.
+type Checkpoint struct {
+	Version int `json:"version"`
+	// Tenant is the tenant the checkpoint was written for, used to catch a
+	// checkpoint file being reused for the wrong migration.
+	Tenant string `json:"tenant"`
+	// MatchHash is a hash of the filter's match expression, used for the
+	// same reason.
+	MatchHash string `json:"match_hash"`
+	// LastCompletedEnd is the end time (RFC3339) of the last chunk whose
+	// ChunkProcessor call returned without error.
+	LastCompletedEnd string `json:"last_completed_end"`
+}
+
+// RetryConfig configures the exponential backoff retry applied to each
+// chunk of a ResumableExport.
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Jitter adds up to this fraction of the computed delay as random
+	// jitter in both directions, e.g. 0.2 for +/-20%.
+	Jitter float64
+}
+
+// DefaultRetryConfig is used by ResumableExport when the zero RetryConfig is passed.
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+	Jitter:      0.2,
+}
+
+// ChunkProcessor is called once per exported chunk with the chunk's native
+// export stream, e.g. to pipe it into ImportPipe. A chunk that fails a
+// retryable way (a server error, a 429, or a mid-stream disconnect) is
+// retried as a whole: re-exported from scratch and handed to process again,
+// so process must tolerate being called more than once for the same time
+// range -- re-importing the same native blocks is idempotent.
+type ChunkProcessor func(ctx context.Context, chunk io.Reader) error
+
+// ResumableExport exports data matching f in chunks of f.Chunk, calling
+// process once per chunk. A checkpoint is written to checkpointPath once
+// process returns nil for a chunk, so restarting with the same
+// checkpointPath resumes after the last chunk process durably handled (e.g.
+// imported into the destination), instead of re-exporting the whole
+// [f.TimeStart, f.TimeEnd] range or re-processing a chunk whose result was
+// already persisted. Pass the zero RetryConfig to use DefaultRetryConfig.
+func (c *Client) ResumableExport(ctx context.Context, url string, f Filter, tenantID, checkpointPath string, retry RetryConfig, process ChunkProcessor) error {
+	start, err := utils.GetTime(f.TimeStart)
+	if err != nil {
+		return fmt.Errorf("failed to parse time start for resumable export: %s", err)
+	}
+	end, err := utils.GetTime(f.TimeEnd)
+	if err != nil {
+		return fmt.Errorf("failed to parse time end for resumable export: %s", err)
+	}
+
+	mHash := matchHash(f.Match)
+	ck, err := loadCheckpoint(checkpointPath)
+	if err != nil {
+		return err
+	}
+	if ck != nil {
+		if ck.Tenant != tenantID || ck.MatchHash != mHash {
+			return fmt.Errorf("checkpoint %q belongs to a different migration (tenant=%q)", checkpointPath, ck.Tenant)
+		}
+		resumeFrom, err := time.Parse(time.RFC3339, ck.LastCompletedEnd)
+		if err != nil {
+			return fmt.Errorf("cannot parse checkpoint resume time %q: %w", ck.LastCompletedEnd, err)
+		}
+		if !resumeFrom.Before(end) {
+			// every chunk was already processed in a previous run
+			return nil
+		}
+		start = resumeFrom
+	}
+
+	ranges, err := stepper.SplitDateRange(start, end, f.Chunk, false)
+	if err != nil {
+		return fmt.Errorf("failed to create date ranges for resumable export: %w", err)
+	}
+
+	if retry == (RetryConfig{}) {
+		retry = DefaultRetryConfig
+	}
+
+	for _, rg := range ranges {
+		cr := chunkRange{start: rg[0], end: rg[1]}
+		if err := c.processChunkWithRetry(ctx, url, f, cr, retry, process); err != nil {
+			return fmt.Errorf("chunk [%s,%s]: %w", cr.start.Format(time.RFC3339), cr.end.Format(time.RFC3339), err)
+		}
+		if err := writeCheckpoint(checkpointPath, tenantID, mHash, cr.end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type chunkRange struct {
+	start, end time.Time
+}
+
+// processChunkWithRetry exports cr and calls process with the result,
+// retrying the whole export+process cycle with exponential backoff when
+// either step fails in a retryable way. Each attempt gets a fresh export
+// stream of the whole chunk, so after a failed attempt process is called
+// again from the chunk's start and may see bytes it has already read.
+func (c *Client) processChunkWithRetry(ctx context.Context, url string, f Filter, cr chunkRange, retry RetryConfig, process ChunkProcessor) error {
+	chunkFilter := f
+	chunkFilter.TimeStart = cr.start.Format(time.RFC3339)
+	chunkFilter.TimeEnd = cr.end.Format(time.RFC3339)
+
+	var lastErr error
+	for attempt := 0; attempt <= retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := backoffDelay(retry, attempt-1, retryAfterOf(lastErr))
+			if err := sleep(ctx, delay); err != nil {
+				return err
+			}
+		}
+
+		rc, err := c.ExportPipe(ctx, url, chunkFilter)
+		if err != nil {
+			lastErr = err
+			if isRetryable(err) {
+				continue
+			}
+			return err
+		}
+
+		perr := process(ctx, rc)
+		if cerr := rc.Close(); perr == nil {
+			perr = cerr
+		}
+		if perr == nil {
+			return nil
+		}
+		lastErr = perr
+		if !isRetryable(perr) {
+			return perr
+		}
+	}
+	return fmt.Errorf("exceeded %d retry attempts: %w", retry.MaxAttempts, lastErr)
+}
+
+func writeCheckpoint(path, tenant, matchHash string, end time.Time) error {
+	ck := Checkpoint{
+		Version:          checkpointVersion,
+		Tenant:           tenant,
+		MatchHash:        matchHash,
+		LastCompletedEnd: end.Format(time.RFC3339),
+	}
+	data, err := json.Marshal(ck)
+	if err != nil {
+		return fmt.Errorf("cannot marshal checkpoint: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("cannot write checkpoint %q: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("cannot persist checkpoint %q: %w", path, err)
+	}
+	return nil
+}
+
+func loadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot read checkpoint %q: %w", path, err)
+	}
+	var ck Checkpoint
+	if err := json.Unmarshal(data, &ck); err != nil {
+		return nil, fmt.Errorf("cannot parse checkpoint %q: %w", path, err)
+	}
+	if ck.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint %q has unsupported version %d, want %d", path, ck.Version, checkpointVersion)
+	}
+	return &ck, nil
+}
+
+func matchHash(match string) string {
+	h := sha256.Sum256([]byte(match))
+	return hex.EncodeToString(h[:])
+}
+
+// isRetryable reports whether err is worth retrying a chunk for: a
+// mid-stream disconnect, a server error, or being rate-limited.
+func isRetryable(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode >= 500 || se.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+func retryAfterOf(err error) time.Duration {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.RetryAfter
+	}
+	return 0
+}
+
+func backoffDelay(retry RetryConfig, attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	d := retry.BaseDelay * time.Duration(math.Pow(2, float64(attempt)))
+	if d > retry.MaxDelay {
+		d = retry.MaxDelay
+	}
+	if retry.Jitter > 0 {
+		jitter := time.Duration(float64(d) * retry.Jitter * (rand.Float64()*2 - 1))
+		d += jitter
+		if d < 0 {
+			d = 0
+		}
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+package native
+
+import (
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer instruments the native client's HTTP calls. Spans are emitted via
+// the globally configured OpenTelemetry TracerProvider, set up out of band
+// via the usual OTEL_EXPORTER_OTLP_ENDPOINT and friends; with no provider
+// configured, otel's default no-op provider makes every call here free.
+var tracer = otel.Tracer("github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/native")
+
+// filterAttributes returns the common span attributes describing filter f,
+// scoped to tenantID when set.
+func filterAttributes(tenantID string, f Filter) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("vmctl.match", f.Match),
+		attribute.String("vmctl.time_start", f.TimeStart),
+		attribute.String("vmctl.time_end", f.TimeEnd),
+	}
+	if tenantID != "" {
+		attrs = append(attrs, attribute.String("vmctl.tenant", tenantID))
+	}
+	return attrs
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
+
+// byteCountingReader tallies the bytes read through it, independently of
+// any Progress metrics, so a span can report vmctl.bytes even when no
+// Progress is configured on the Client. It wraps an io.ReadCloser, not a
+// bare io.Reader, and forwards Close so callers can still pass it as an
+// HTTP request body without losing the underlying pipe's close contract.
+type byteCountingReader struct {
+	rc io.ReadCloser
+	n  int64
+}
+
+func (b *byteCountingReader) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *byteCountingReader) Close() error {
+	return b.rc.Close()
+}
+
+// tracedReadCloser keeps span open for the lifetime of the wrapped
+// io.ReadCloser, so a chunk's span covers the whole transfer rather than
+// just the initial request, and records the number of bytes streamed
+// through it once closed.
+type tracedReadCloser struct {
+	io.ReadCloser
+	span  trace.Span
+	bytes int64
+}
+
+func (t *tracedReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.bytes += int64(n)
+	return n, err
+}
+
+func (t *tracedReadCloser) Close() error {
+	t.span.SetAttributes(attribute.Int64("vmctl.bytes", t.bytes))
+	err := t.ReadCloser.Close()
+	endSpan(t.span, err)
+	return err
+}